@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/aws-sdk-go/aws"
+	"github.com/hashicorp/aws-sdk-go/gen/ec2"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceAwsMainRouteTableAssociation() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsMainRouteTableAssociationCreate,
+		Read:   resourceAwsMainRouteTableAssociationRead,
+		Update: resourceAwsMainRouteTableAssociationUpdate,
+		Delete: resourceAwsMainRouteTableAssociationDelete,
+
+		Schema: map[string]*schema.Schema{
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+
+			"route_table_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+
+			"original_route_table_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceAwsMainRouteTableAssociationCreate(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	vpcID := d.Get("vpc_id").(string)
+	routeTableID := d.Get("route_table_id").(string)
+
+	mainAssociationID, originalRouteTableID, err := resourceAwsRouteTableFindMainAssociationID(ec2conn, vpcID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf(
+		"[INFO] Creating main route table association: %s => %s",
+		vpcID, routeTableID)
+
+	resp, err := ec2conn.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationRequest{
+		AssociationID: aws.String(mainAssociationID),
+		RouteTableID:  aws.String(routeTableID),
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating main route table association: %s", err)
+	}
+
+	if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, routeTableID, *resp.NewAssociationID, defaultRouteTableAssociationTimeout); err != nil {
+		return err
+	}
+
+	d.Set("original_route_table_id", originalRouteTableID)
+	d.SetId(*resp.NewAssociationID)
+	log.Printf("[INFO] Association ID: %s", d.Id())
+
+	return nil
+}
+
+func resourceAwsMainRouteTableAssociationRead(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	rtRaw, _, err := resourceAwsRouteTableStateRefreshFunc(
+		ec2conn, d.Get("route_table_id").(string))()
+	if err != nil {
+		return err
+	}
+	if rtRaw == nil {
+		d.SetId("")
+		return nil
+	}
+	rt := rtRaw.(*ec2.RouteTable)
+
+	found := false
+	for _, a := range rt.Associations {
+		if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == d.Id() {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		d.SetId("")
+	}
+
+	return nil
+}
+
+func resourceAwsMainRouteTableAssociationUpdate(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	routeTableID := d.Get("route_table_id").(string)
+
+	log.Printf(
+		"[INFO] Updating main route table association: %s => %s",
+		d.Id(), routeTableID)
+
+	req := &ec2.ReplaceRouteTableAssociationRequest{
+		AssociationID: aws.String(d.Id()),
+		RouteTableID:  aws.String(routeTableID),
+	}
+	resp, err := ec2conn.ReplaceRouteTableAssociation(req)
+	if err != nil {
+		return fmt.Errorf("Error updating main route table association: %s", err)
+	}
+
+	if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, routeTableID, *resp.NewAssociationID, defaultRouteTableAssociationTimeout); err != nil {
+		return err
+	}
+
+	d.SetId(*resp.NewAssociationID)
+	log.Printf("[INFO] Association ID: %s", d.Id())
+
+	return nil
+}
+
+func resourceAwsMainRouteTableAssociationDelete(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	originalRouteTableID := d.Get("original_route_table_id").(string)
+
+	log.Printf(
+		"[INFO] Restoring original main route table association: %s => %s",
+		d.Id(), originalRouteTableID)
+
+	_, err := ec2conn.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationRequest{
+		AssociationID: aws.String(d.Id()),
+		RouteTableID:  aws.String(originalRouteTableID),
+	})
+	if err != nil {
+		ec2err, ok := err.(aws.APIError)
+		if ok && ec2err.Code == "InvalidAssociationID.NotFound" {
+			return nil
+		}
+
+		return fmt.Errorf("Error restoring original main route table association: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/aws-sdk-go/aws"
+	"github.com/hashicorp/aws-sdk-go/gen/ec2"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccAWSMainRouteTableAssociation_basic(t *testing.T) {
+	var rt ec2.RouteTable
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckMainRouteTableAssociationDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccMainRouteTableAssociationConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckMainRouteTableAssociationExists(
+						"aws_main_route_table_association.foo", &rt),
+				),
+			},
+
+			resource.TestStep{
+				Config: testAccMainRouteTableAssociationConfigUpdate,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckMainRouteTableAssociationExists(
+						"aws_main_route_table_association.foo", &rt),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckMainRouteTableAssociationDestroy(s *terraform.State) error {
+	conn := testAccProvider.Meta().(*AWSClient).awsEC2conn
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "aws_main_route_table_association" {
+			continue
+		}
+
+		resp, err := conn.DescribeRouteTables(&ec2.DescribeRouteTablesRequest{
+			Filters: []ec2.Filter{
+				ec2.Filter{Name: aws.String("association.main"), Values: []string{"true"}},
+				ec2.Filter{Name: aws.String("vpc-id"), Values: []string{rs.Primary.Attributes["vpc_id"]}},
+			},
+		})
+		if err != nil {
+			ec2err, ok := err.(aws.APIError)
+			if ok && ec2err.Code == "InvalidVpcID.NotFound" {
+				continue
+			}
+			return err
+		}
+		if len(resp.RouteTables) != 1 {
+			continue
+		}
+
+		// Destroy should have restored the VPC's original main
+		// association, so this resource's association ID must no
+		// longer be the one marked as main.
+		for _, a := range resp.RouteTables[0].Associations {
+			if a.Main != nil && *a.Main && a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == rs.Primary.ID {
+				return fmt.Errorf("Main route table association was not restored: %s", rs.Primary.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func testAccCheckMainRouteTableAssociationExists(n string, rt *ec2.RouteTable) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No ID is set")
+		}
+
+		conn := testAccProvider.Meta().(*AWSClient).awsEC2conn
+		resp, err := conn.DescribeRouteTables(&ec2.DescribeRouteTablesRequest{
+			RouteTableIDs: []string{rs.Primary.Attributes["route_table_id"]},
+		})
+		if err != nil {
+			return err
+		}
+		if len(resp.RouteTables) == 0 {
+			return fmt.Errorf("Route table not found")
+		}
+
+		for _, a := range resp.RouteTables[0].Associations {
+			if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == rs.Primary.ID {
+				if a.Main == nil || !*a.Main {
+					return fmt.Errorf("Association %s is not the main association", rs.Primary.ID)
+				}
+				*rt = resp.RouteTables[0]
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Main route table association not found: %s", rs.Primary.ID)
+	}
+}
+
+const testAccMainRouteTableAssociationConfig = `
+resource "aws_vpc" "foo" {
+	cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_route_table" "foo" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_main_route_table_association" "foo" {
+	vpc_id         = "${aws_vpc.foo.id}"
+	route_table_id = "${aws_route_table.foo.id}"
+}
+`
+
+const testAccMainRouteTableAssociationConfigUpdate = `
+resource "aws_vpc" "foo" {
+	cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_route_table" "foo" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_route_table" "bar" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_main_route_table_association" "foo" {
+	vpc_id         = "${aws_vpc.foo.id}"
+	route_table_id = "${aws_route_table.bar.id}"
+}
+`
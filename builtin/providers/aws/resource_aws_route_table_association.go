@@ -3,12 +3,20 @@ package aws
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/aws-sdk-go/aws"
 	"github.com/hashicorp/aws-sdk-go/gen/ec2"
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
+// defaultRouteTableAssociationTimeout is how long Create/Update will wait
+// for a new association to show up in DescribeRouteTables before giving
+// up, to ride out EC2's eventual consistency.
+const defaultRouteTableAssociationTimeout = 2 * time.Minute
+
 func resourceAwsRouteTableAssociation() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceAwsRouteTableAssociationCreate,
@@ -17,9 +25,17 @@ func resourceAwsRouteTableAssociation() *schema.Resource {
 		Delete: resourceAwsRouteTableAssociationDelete,
 
 		Schema: map[string]*schema.Schema{
-			"subnet_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
+			"subnet_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+
+			"main": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
 				ForceNew: true,
 			},
 
@@ -27,34 +43,215 @@ func resourceAwsRouteTableAssociation() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+
+			"timeout": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "2m",
+			},
 		},
 	}
 }
 
+// resourceAwsRouteTableAssociationTimeout returns the duration Create and
+// Update should wait for an association to become visible before failing.
+func resourceAwsRouteTableAssociationTimeout(d *schema.ResourceData) (time.Duration, error) {
+	raw := d.Get("timeout").(string)
+	if raw == "" {
+		return defaultRouteTableAssociationTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("aws_route_table_association: invalid timeout %q: %s", raw, err)
+	}
+
+	return timeout, nil
+}
+
+// resourceAwsRouteTableAssociationWaitForState polls DescribeRouteTables
+// until associationID shows up among routeTableID's associations, to cover
+// for AssociateRouteTable/ReplaceRouteTableAssociation not being
+// read-after-write consistent.
+func resourceAwsRouteTableAssociationWaitForState(ec2conn *ec2.EC2, routeTableID, associationID string, timeout time.Duration) error {
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"pending"},
+		Target:     "ready",
+		Refresh:    resourceAwsRouteTableAssociationRefreshFunc(ec2conn, routeTableID, associationID),
+		Timeout:    timeout,
+		Delay:      2 * time.Second,
+		MinTimeout: 2 * time.Second,
+	}
+
+	_, err := stateConf.WaitForState()
+	if err != nil {
+		return fmt.Errorf("Error waiting for route table association %s to become visible: %s", associationID, err)
+	}
+
+	return nil
+}
+
+func resourceAwsRouteTableAssociationRefreshFunc(ec2conn *ec2.EC2, routeTableID, associationID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		rtRaw, _, err := resourceAwsRouteTableStateRefreshFunc(ec2conn, routeTableID)()
+		if err != nil {
+			return nil, "", err
+		}
+		if rtRaw == nil {
+			return nil, "pending", nil
+		}
+		rt := rtRaw.(*ec2.RouteTable)
+
+		for _, a := range rt.Associations {
+			if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == associationID {
+				return rt, "ready", nil
+			}
+		}
+
+		return rt, "pending", nil
+	}
+}
+
 func resourceAwsRouteTableAssociationCreate(d *schema.ResourceData, meta interface{}) error {
 	ec2conn := meta.(*AWSClient).awsEC2conn
 
+	subnetIDs := expandStringList(d.Get("subnet_ids").(*schema.Set).List())
+	main := d.Get("main").(bool)
+
+	if main {
+		if len(subnetIDs) > 0 {
+			return fmt.Errorf("aws_route_table_association: subnet_ids cannot be set when main is true")
+		}
+		return resourceAwsRouteTableAssociationCreateMain(d, meta)
+	}
+
+	if len(subnetIDs) == 0 {
+		return fmt.Errorf("aws_route_table_association: subnet_ids must contain at least one subnet when main is false")
+	}
+
+	routeTableID := d.Get("route_table_id").(string)
+
+	timeout, err := resourceAwsRouteTableAssociationTimeout(d)
+	if err != nil {
+		return err
+	}
+
+	var associationIDs []string
+	for _, subnetID := range subnetIDs {
+		log.Printf(
+			"[INFO] Creating route table association: %s => %s",
+			subnetID, routeTableID)
+
+		resp, err := ec2conn.AssociateRouteTable(&ec2.AssociateRouteTableRequest{
+			RouteTableID: aws.String(routeTableID),
+			SubnetID:     aws.String(subnetID),
+		})
+		if err != nil {
+			// Roll back any associations we already created for this
+			// resource rather than leaving them orphaned with no ID
+			// Terraform can use to clean them up later.
+			for _, id := range associationIDs {
+				if disErr := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{AssociationID: aws.String(id)}); disErr != nil {
+					log.Printf("[WARN] Failed to roll back route table association %s: %s", id, disErr)
+				}
+			}
+			return fmt.Errorf("Error associating subnet %s with route table %s: %s", subnetID, routeTableID, err)
+		}
+
+		if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, routeTableID, *resp.AssociationID, timeout); err != nil {
+			// The association exists in AWS even though we never
+			// observed it; roll it back along with the rest so we
+			// don't leak associations Terraform has no ID to track.
+			associationIDs = append(associationIDs, *resp.AssociationID)
+			for _, id := range associationIDs {
+				if disErr := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{AssociationID: aws.String(id)}); disErr != nil {
+					log.Printf("[WARN] Failed to roll back route table association %s: %s", id, disErr)
+				}
+			}
+			return err
+		}
+
+		associationIDs = append(associationIDs, *resp.AssociationID)
+	}
+
+	d.SetId(strings.Join(associationIDs, ","))
+	log.Printf("[INFO] Association ID: %s", d.Id())
+
+	return nil
+}
+
+func resourceAwsRouteTableAssociationCreateMain(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	routeTableID := d.Get("route_table_id").(string)
+
+	rtRaw, _, err := resourceAwsRouteTableStateRefreshFunc(ec2conn, routeTableID)()
+	if err != nil {
+		return err
+	}
+	if rtRaw == nil {
+		return fmt.Errorf("Route table %s not found", routeTableID)
+	}
+	rt := rtRaw.(*ec2.RouteTable)
+
+	mainAssociationID, _, err := resourceAwsRouteTableFindMainAssociationID(ec2conn, *rt.VPCID)
+	if err != nil {
+		return err
+	}
+
 	log.Printf(
-		"[INFO] Creating route table association: %s => %s",
-		d.Get("subnet_id").(string),
-		d.Get("route_table_id").(string))
+		"[INFO] Taking over main route table association: %s => %s",
+		mainAssociationID, routeTableID)
 
-	resp, err := ec2conn.AssociateRouteTable(&ec2.AssociateRouteTableRequest{
-		RouteTableID: aws.String(d.Get("route_table_id").(string)),
-		SubnetID:     aws.String(d.Get("subnet_id").(string)),
+	resp, err := ec2conn.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationRequest{
+		AssociationID: aws.String(mainAssociationID),
+		RouteTableID:  aws.String(routeTableID),
 	})
+	if err != nil {
+		return fmt.Errorf("Error taking over main route table association: %s", err)
+	}
 
+	timeout, err := resourceAwsRouteTableAssociationTimeout(d)
 	if err != nil {
 		return err
 	}
+	if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, routeTableID, *resp.NewAssociationID, timeout); err != nil {
+		return err
+	}
 
-	// Set the ID and return
-	d.SetId(*resp.AssociationID)
+	d.SetId(*resp.NewAssociationID)
 	log.Printf("[INFO] Association ID: %s", d.Id())
 
 	return nil
 }
 
+// resourceAwsRouteTableFindMainAssociationID returns the association ID of
+// the route table association currently marked as main for the given VPC,
+// along with the route table ID it's currently pointed at.
+func resourceAwsRouteTableFindMainAssociationID(ec2conn *ec2.EC2, vpcID string) (string, string, error) {
+	resp, err := ec2conn.DescribeRouteTables(&ec2.DescribeRouteTablesRequest{
+		Filters: []ec2.Filter{
+			ec2.Filter{Name: aws.String("association.main"), Values: []string{"true"}},
+			ec2.Filter{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+		},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("Error describing route tables for VPC %s: %s", vpcID, err)
+	}
+	if len(resp.RouteTables) != 1 {
+		return "", "", fmt.Errorf("Expected to find a single main route table for VPC %s", vpcID)
+	}
+
+	rt := resp.RouteTables[0]
+	for _, a := range rt.Associations {
+		if a.Main != nil && *a.Main {
+			return *a.RouteTableAssociationID, *rt.RouteTableID, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("Could not find main route table association for VPC %s", vpcID)
+}
+
 func resourceAwsRouteTableAssociationRead(d *schema.ResourceData, meta interface{}) error {
 	ec2conn := meta.(*AWSClient).awsEC2conn
 
@@ -65,73 +262,269 @@ func resourceAwsRouteTableAssociationRead(d *schema.ResourceData, meta interface
 		return err
 	}
 	if rtRaw == nil {
+		d.SetId("")
 		return nil
 	}
 	rt := rtRaw.(*ec2.RouteTable)
 
-	// Inspect that the association exists
-	found := false
+	if d.Get("main").(bool) {
+		for _, a := range rt.Associations {
+			if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == d.Id() {
+				return nil
+			}
+		}
+		d.SetId("")
+		return nil
+	}
+
+	wantIDs := make(map[string]bool)
+	for _, id := range strings.Split(d.Id(), ",") {
+		wantIDs[id] = true
+	}
+
+	var stillAssociated []string
+	subnetIDs := &schema.Set{F: schema.HashString}
 	for _, a := range rt.Associations {
-		if *a.RouteTableAssociationID == d.Id() {
-			found = true
-			d.Set("subnet_id", *a.SubnetID)
-			break
+		if a.RouteTableAssociationID == nil || !wantIDs[*a.RouteTableAssociationID] {
+			continue
+		}
+		stillAssociated = append(stillAssociated, *a.RouteTableAssociationID)
+		if a.SubnetID != nil {
+			subnetIDs.Add(*a.SubnetID)
 		}
 	}
 
-	if !found {
-		// It seems it doesn't exist anymore, so clear the ID
+	if len(stillAssociated) == 0 {
+		// None of the associations we created still exist.
 		d.SetId("")
+		return nil
 	}
 
+	d.Set("subnet_ids", subnetIDs)
+	d.SetId(strings.Join(stillAssociated, ","))
+
 	return nil
 }
 
 func resourceAwsRouteTableAssociationUpdate(d *schema.ResourceData, meta interface{}) error {
 	ec2conn := meta.(*AWSClient).awsEC2conn
 
-	log.Printf(
-		"[INFO] Creating route table association: %s => %s",
-		d.Get("subnet_id").(string),
-		d.Get("route_table_id").(string))
+	if d.Get("main").(bool) {
+		if d.Get("subnet_ids").(*schema.Set).Len() > 0 {
+			return fmt.Errorf("aws_route_table_association: subnet_ids cannot be set when main is true")
+		}
+		return resourceAwsRouteTableAssociationUpdateMain(d, meta)
+	}
 
-	req := &ec2.ReplaceRouteTableAssociationRequest{
-		AssociationID: aws.String(d.Id()),
-		RouteTableID:  aws.String(d.Get("route_table_id").(string)),
+	oldRouteTableIDRaw, newRouteTableIDRaw := d.GetChange("route_table_id")
+	oldRouteTableID := oldRouteTableIDRaw.(string)
+	newRouteTableID := newRouteTableIDRaw.(string)
+
+	desired := make(map[string]bool)
+	for _, s := range d.Get("subnet_ids").(*schema.Set).List() {
+		desired[s.(string)] = true
 	}
-	resp, err := ec2conn.ReplaceRouteTableAssociation(req)
 
+	current, err := resourceAwsRouteTableAssociationCurrentBySubnet(
+		ec2conn, oldRouteTableID, strings.Split(d.Id(), ","))
 	if err != nil {
-		ec2err, ok := err.(aws.APIError)
-		if ok && ec2err.Code == "InvalidAssociationID.NotFound" {
-			// Not found, so just create a new one
-			return resourceAwsRouteTableAssociationCreate(d, meta)
-		}
+		return err
+	}
 
+	timeout, err := resourceAwsRouteTableAssociationTimeout(d)
+	if err != nil {
 		return err
 	}
 
-	// Update the ID
-	d.SetId(*resp.NewAssociationID)
+	var associationIDs []string
+
+	// Update associations we're keeping (possibly onto a new route table)
+	// and disassociate any subnets that were dropped from the set. If we
+	// bail out partway through, persist whatever associationIDs we've
+	// already confirmed so Terraform's state doesn't point at IDs that
+	// Replace has since invalidated.
+	for subnetID, associationID := range current {
+		if !desired[subnetID] {
+			log.Printf("[INFO] Disassociating route table association: %s", associationID)
+			if err := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{
+				AssociationID: aws.String(associationID),
+			}); err != nil {
+				d.SetId(strings.Join(associationIDs, ","))
+				return fmt.Errorf("Error disassociating subnet %s: %s", subnetID, err)
+			}
+			continue
+		}
+
+		if oldRouteTableID == newRouteTableID {
+			// Nothing changed for this subnet; keep its association as-is.
+			associationIDs = append(associationIDs, associationID)
+			delete(desired, subnetID)
+			continue
+		}
+
+		log.Printf(
+			"[INFO] Updating route table association: %s => %s",
+			subnetID, newRouteTableID)
+		resp, err := ec2conn.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationRequest{
+			AssociationID: aws.String(associationID),
+			RouteTableID:  aws.String(newRouteTableID),
+		})
+		if err != nil {
+			d.SetId(strings.Join(associationIDs, ","))
+			return fmt.Errorf("Error updating association for subnet %s: %s", subnetID, err)
+		}
+
+		if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, newRouteTableID, *resp.NewAssociationID, timeout); err != nil {
+			associationIDs = append(associationIDs, *resp.NewAssociationID)
+			d.SetId(strings.Join(associationIDs, ","))
+			return err
+		}
+
+		associationIDs = append(associationIDs, *resp.NewAssociationID)
+		delete(desired, subnetID)
+	}
+
+	// Associate any newly added subnets.
+	var newlyCreated []string
+	for subnetID := range desired {
+		log.Printf(
+			"[INFO] Creating route table association: %s => %s",
+			subnetID, newRouteTableID)
+		resp, err := ec2conn.AssociateRouteTable(&ec2.AssociateRouteTableRequest{
+			RouteTableID: aws.String(newRouteTableID),
+			SubnetID:     aws.String(subnetID),
+		})
+		if err != nil {
+			// Roll back any associations created earlier in this update
+			// before surfacing the error, but keep the ones that were
+			// only kept/replaced above so state still matches reality.
+			for _, id := range newlyCreated {
+				if disErr := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{AssociationID: aws.String(id)}); disErr != nil {
+					log.Printf("[WARN] Failed to roll back route table association %s: %s", id, disErr)
+				}
+			}
+			d.SetId(strings.Join(associationIDs, ","))
+			return fmt.Errorf("Error associating subnet %s with route table %s: %s", subnetID, newRouteTableID, err)
+		}
+
+		if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, newRouteTableID, *resp.AssociationID, timeout); err != nil {
+			// Unlike the replace case above, a brand new association can
+			// be cleanly undone, so roll this one back too along with
+			// anything else created earlier in this loop.
+			newlyCreated = append(newlyCreated, *resp.AssociationID)
+			for _, id := range newlyCreated {
+				if disErr := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{AssociationID: aws.String(id)}); disErr != nil {
+					log.Printf("[WARN] Failed to roll back route table association %s: %s", id, disErr)
+				}
+			}
+			d.SetId(strings.Join(associationIDs, ","))
+			return err
+		}
+
+		newlyCreated = append(newlyCreated, *resp.AssociationID)
+	}
+
+	associationIDs = append(associationIDs, newlyCreated...)
+	d.SetId(strings.Join(associationIDs, ","))
 	log.Printf("[INFO] Association ID: %s", d.Id())
 
 	return nil
 }
 
-func resourceAwsRouteTableAssociationDelete(d *schema.ResourceData, meta interface{}) error {
+func resourceAwsRouteTableAssociationUpdateMain(d *schema.ResourceData, meta interface{}) error {
 	ec2conn := meta.(*AWSClient).awsEC2conn
 
-	log.Printf("[INFO] Deleting route table association: %s", d.Id())
-	err := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{
+	routeTableID := d.Get("route_table_id").(string)
+
+	log.Printf(
+		"[INFO] Updating main route table association: %s => %s",
+		d.Id(), routeTableID)
+
+	resp, err := ec2conn.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationRequest{
 		AssociationID: aws.String(d.Id()),
+		RouteTableID:  aws.String(routeTableID),
 	})
 	if err != nil {
 		ec2err, ok := err.(aws.APIError)
 		if ok && ec2err.Code == "InvalidAssociationID.NotFound" {
-			return nil
+			// Not found, so just take over the main association again
+			return resourceAwsRouteTableAssociationCreateMain(d, meta)
 		}
 
-		return fmt.Errorf("Error deleting route table association: %s", err)
+		return fmt.Errorf("Error updating main route table association: %s", err)
+	}
+
+	timeout, err := resourceAwsRouteTableAssociationTimeout(d)
+	if err != nil {
+		return err
+	}
+	if err := resourceAwsRouteTableAssociationWaitForState(ec2conn, routeTableID, *resp.NewAssociationID, timeout); err != nil {
+		return err
+	}
+
+	d.SetId(*resp.NewAssociationID)
+	log.Printf("[INFO] Association ID: %s", d.Id())
+
+	return nil
+}
+
+// resourceAwsRouteTableAssociationCurrentBySubnet looks up the subset of
+// associationIDs that are still attached to routeTableID and returns them
+// keyed by the subnet they belong to.
+func resourceAwsRouteTableAssociationCurrentBySubnet(ec2conn *ec2.EC2, routeTableID string, associationIDs []string) (map[string]string, error) {
+	rtRaw, _, err := resourceAwsRouteTableStateRefreshFunc(ec2conn, routeTableID)()
+	if err != nil {
+		return nil, err
+	}
+	if rtRaw == nil {
+		return nil, nil
+	}
+	rt := rtRaw.(*ec2.RouteTable)
+
+	want := make(map[string]bool)
+	for _, id := range associationIDs {
+		want[id] = true
+	}
+
+	current := make(map[string]string)
+	for _, a := range rt.Associations {
+		if a.RouteTableAssociationID == nil || a.SubnetID == nil || !want[*a.RouteTableAssociationID] {
+			continue
+		}
+		current[*a.SubnetID] = *a.RouteTableAssociationID
+	}
+
+	return current, nil
+}
+
+func resourceAwsRouteTableAssociationDelete(d *schema.ResourceData, meta interface{}) error {
+	ec2conn := meta.(*AWSClient).awsEC2conn
+
+	if d.Get("main").(bool) {
+		// A main route table association can only be replaced, never
+		// removed outright, so there's nothing to call here; Terraform
+		// just forgets about it.
+		log.Printf("[INFO] Main route table association %s cannot be deleted, removing from state", d.Id())
+		return nil
+	}
+
+	var errs []string
+	for _, associationID := range strings.Split(d.Id(), ",") {
+		log.Printf("[INFO] Deleting route table association: %s", associationID)
+		err := ec2conn.DisassociateRouteTable(&ec2.DisassociateRouteTableRequest{
+			AssociationID: aws.String(associationID),
+		})
+		if err != nil {
+			ec2err, ok := err.(aws.APIError)
+			if ok && ec2err.Code == "InvalidAssociationID.NotFound" {
+				continue
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", associationID, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Error deleting route table association(s): %s", strings.Join(errs, "; "))
 	}
 
 	return nil
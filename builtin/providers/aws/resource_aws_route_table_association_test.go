@@ -0,0 +1,221 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/aws-sdk-go/aws"
+	"github.com/hashicorp/aws-sdk-go/gen/ec2"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccAWSRouteTableAssociation_basic(t *testing.T) {
+	var rt ec2.RouteTable
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRouteTableAssociationDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccRouteTableAssociationConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRouteTableAssociationExists(
+						"aws_route_table_association.foo", &rt),
+					resource.TestCheckResourceAttr(
+						"aws_route_table_association.foo", "subnet_ids.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAWSRouteTableAssociation_multipleSubnets(t *testing.T) {
+	var rt ec2.RouteTable
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRouteTableAssociationDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccRouteTableAssociationConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRouteTableAssociationExists(
+						"aws_route_table_association.foo", &rt),
+					resource.TestCheckResourceAttr(
+						"aws_route_table_association.foo", "subnet_ids.#", "1"),
+				),
+			},
+
+			resource.TestStep{
+				Config: testAccRouteTableAssociationConfigMultipleSubnets,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRouteTableAssociationExists(
+						"aws_route_table_association.foo", &rt),
+					resource.TestCheckResourceAttr(
+						"aws_route_table_association.foo", "subnet_ids.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAWSRouteTableAssociation_main(t *testing.T) {
+	var rt ec2.RouteTable
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRouteTableAssociationDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccRouteTableAssociationConfigMain,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRouteTableAssociationExists(
+						"aws_route_table_association.foo", &rt),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckRouteTableAssociationDestroy(s *terraform.State) error {
+	conn := testAccProvider.Meta().(*AWSClient).awsEC2conn
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "aws_route_table_association" {
+			continue
+		}
+
+		// A main route table association can only be replaced, never
+		// removed, so Delete deliberately leaves it in place; there's
+		// nothing to assert gone here.
+		if rs.Primary.Attributes["main"] == "true" {
+			continue
+		}
+
+		resp, err := conn.DescribeRouteTables(&ec2.DescribeRouteTablesRequest{
+			RouteTableIDs: []string{rs.Primary.Attributes["route_table_id"]},
+		})
+		if err != nil {
+			ec2err, ok := err.(aws.APIError)
+			if ok && ec2err.Code == "InvalidRouteTableID.NotFound" {
+				continue
+			}
+			return err
+		}
+		if len(resp.RouteTables) == 0 {
+			continue
+		}
+
+		ids := strings.Split(rs.Primary.ID, ",")
+		for _, a := range resp.RouteTables[0].Associations {
+			for _, id := range ids {
+				if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == id {
+					return fmt.Errorf("Route table association still exists: %s", id)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func testAccCheckRouteTableAssociationExists(n string, rt *ec2.RouteTable) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No ID is set")
+		}
+
+		conn := testAccProvider.Meta().(*AWSClient).awsEC2conn
+		resp, err := conn.DescribeRouteTables(&ec2.DescribeRouteTablesRequest{
+			RouteTableIDs: []string{rs.Primary.Attributes["route_table_id"]},
+		})
+		if err != nil {
+			return err
+		}
+		if len(resp.RouteTables) == 0 {
+			return fmt.Errorf("Route table not found")
+		}
+
+		ids := strings.Split(rs.Primary.ID, ",")
+		for _, a := range resp.RouteTables[0].Associations {
+			for _, id := range ids {
+				if a.RouteTableAssociationID != nil && *a.RouteTableAssociationID == id {
+					*rt = resp.RouteTables[0]
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("Route table association not found: %s", rs.Primary.ID)
+	}
+}
+
+const testAccRouteTableAssociationConfig = `
+resource "aws_vpc" "foo" {
+	cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_subnet" "foo" {
+	vpc_id     = "${aws_vpc.foo.id}"
+	cidr_block = "10.1.1.0/24"
+}
+
+resource "aws_route_table" "foo" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_route_table_association" "foo" {
+	route_table_id = "${aws_route_table.foo.id}"
+	subnet_ids     = ["${aws_subnet.foo.id}"]
+}
+`
+
+const testAccRouteTableAssociationConfigMultipleSubnets = `
+resource "aws_vpc" "foo" {
+	cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_subnet" "foo" {
+	vpc_id     = "${aws_vpc.foo.id}"
+	cidr_block = "10.1.1.0/24"
+}
+
+resource "aws_subnet" "bar" {
+	vpc_id     = "${aws_vpc.foo.id}"
+	cidr_block = "10.1.2.0/24"
+}
+
+resource "aws_route_table" "foo" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_route_table_association" "foo" {
+	route_table_id = "${aws_route_table.foo.id}"
+	subnet_ids     = ["${aws_subnet.foo.id}", "${aws_subnet.bar.id}"]
+}
+`
+
+const testAccRouteTableAssociationConfigMain = `
+resource "aws_vpc" "foo" {
+	cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_route_table" "foo" {
+	vpc_id = "${aws_vpc.foo.id}"
+}
+
+resource "aws_route_table_association" "foo" {
+	route_table_id = "${aws_route_table.foo.id}"
+	main           = true
+}
+`